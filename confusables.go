@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// confusablesData is a curated subset of the Unicode confusables table
+// (UTS #39, https://www.unicode.org/reports/tr39/), in the same
+// "source ; target ; # comment" shape as the official confusablesSummary.txt.
+// It covers common Latin look-alikes from Cyrillic and Greek used in
+// homoglyph/transliteration attacks against internationalized passwords.
+// Paste in rows from the full table in the same format to extend coverage.
+const confusablesData = `
+0430 ; 0061 ; # CYRILLIC SMALL LETTER A → LATIN SMALL LETTER A
+0435 ; 0065 ; # CYRILLIC SMALL LETTER IE → LATIN SMALL LETTER E
+0451 ; 0065 ; # CYRILLIC SMALL LETTER IO → LATIN SMALL LETTER E
+043E ; 006F ; # CYRILLIC SMALL LETTER O → LATIN SMALL LETTER O
+0440 ; 0070 ; # CYRILLIC SMALL LETTER ER → LATIN SMALL LETTER P
+0441 ; 0063 ; # CYRILLIC SMALL LETTER ES → LATIN SMALL LETTER C
+0443 ; 0079 ; # CYRILLIC SMALL LETTER U → LATIN SMALL LETTER Y
+0445 ; 0078 ; # CYRILLIC SMALL LETTER HA → LATIN SMALL LETTER X
+0410 ; 0041 ; # CYRILLIC CAPITAL LETTER A → LATIN CAPITAL LETTER A
+0412 ; 0042 ; # CYRILLIC CAPITAL LETTER VE → LATIN CAPITAL LETTER B
+0415 ; 0045 ; # CYRILLIC CAPITAL LETTER IE → LATIN CAPITAL LETTER E
+041A ; 004B ; # CYRILLIC CAPITAL LETTER KA → LATIN CAPITAL LETTER K
+041C ; 004D ; # CYRILLIC CAPITAL LETTER EM → LATIN CAPITAL LETTER M
+041D ; 0048 ; # CYRILLIC CAPITAL LETTER EN → LATIN CAPITAL LETTER H
+041E ; 004F ; # CYRILLIC CAPITAL LETTER O → LATIN CAPITAL LETTER O
+0420 ; 0050 ; # CYRILLIC CAPITAL LETTER ER → LATIN CAPITAL LETTER P
+0421 ; 0043 ; # CYRILLIC CAPITAL LETTER ES → LATIN CAPITAL LETTER C
+0422 ; 0054 ; # CYRILLIC CAPITAL LETTER TE → LATIN CAPITAL LETTER T
+0425 ; 0058 ; # CYRILLIC CAPITAL LETTER HA → LATIN CAPITAL LETTER X
+0391 ; 0041 ; # GREEK CAPITAL LETTER ALPHA → LATIN CAPITAL LETTER A
+0392 ; 0042 ; # GREEK CAPITAL LETTER BETA → LATIN CAPITAL LETTER B
+0395 ; 0045 ; # GREEK CAPITAL LETTER EPSILON → LATIN CAPITAL LETTER E
+0396 ; 005A ; # GREEK CAPITAL LETTER ZETA → LATIN CAPITAL LETTER Z
+0397 ; 0048 ; # GREEK CAPITAL LETTER ETA → LATIN CAPITAL LETTER H
+0399 ; 0049 ; # GREEK CAPITAL LETTER IOTA → LATIN CAPITAL LETTER I
+039A ; 004B ; # GREEK CAPITAL LETTER KAPPA → LATIN CAPITAL LETTER K
+039C ; 004D ; # GREEK CAPITAL LETTER MU → LATIN CAPITAL LETTER M
+039D ; 004E ; # GREEK CAPITAL LETTER NU → LATIN CAPITAL LETTER N
+039F ; 004F ; # GREEK CAPITAL LETTER OMICRON → LATIN CAPITAL LETTER O
+03A1 ; 0050 ; # GREEK CAPITAL LETTER RHO → LATIN CAPITAL LETTER P
+03A4 ; 0054 ; # GREEK CAPITAL LETTER TAU → LATIN CAPITAL LETTER T
+03A7 ; 0058 ; # GREEK CAPITAL LETTER CHI → LATIN CAPITAL LETTER X
+03B1 ; 0061 ; # GREEK SMALL LETTER ALPHA → LATIN SMALL LETTER A
+03BF ; 006F ; # GREEK SMALL LETTER OMICRON → LATIN SMALL LETTER O
+0131 ; 0069 ; # LATIN SMALL LETTER DOTLESS I → LATIN SMALL LETTER I
+1E9E ; 0053 ; # LATIN CAPITAL LETTER SHARP S → LATIN CAPITAL LETTER S
+`
+
+// confusablesMap parses confusablesData into a substitution map of source
+// rune to target rune, in the same shape readSubstitutionTable produces, so
+// it can be merged directly into main's substitutionMap.
+func confusablesMap() map[string][]string {
+	subs := make(map[string][]string)
+	scanner := bufio.NewScanner(strings.NewReader(confusablesData))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, ";", 3)
+		if len(fields) < 2 {
+			continue
+		}
+
+		source, err := decodeCodepoint(strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue
+		}
+		target, err := decodeCodepoint(strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+
+		subs[source] = append(subs[source], target)
+	}
+	return subs
+}
+
+// decodeCodepoint parses a single Unicode code point written as hex, the
+// way UTS #39's confusables table does, into its UTF-8 string form.
+func decodeCodepoint(hexCodepoint string) (string, error) {
+	var r rune
+	if _, err := fmt.Sscanf(hexCodepoint, "%X", &r); err != nil {
+		return "", err
+	}
+	return string(r), nil
+}
@@ -0,0 +1,100 @@
+// Package dedup provides candidate deduplication filters that sit between
+// the substitution workers and the output writer so that variations which
+// converge on the same word from different substitution paths are only
+// written once.
+package dedup
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/bits-and-blooms/bloom/v3"
+	"github.com/cespare/xxhash/v2"
+)
+
+// Filter decides whether a candidate has already been emitted. Seen records
+// the candidate and reports whether it has been seen before: false the first
+// time it is called for a given word, true on every subsequent call for that
+// same word. Implementations must be safe for concurrent use, since workers
+// call Seen from multiple goroutines.
+//
+// Users who need deduplication across multiple runs (e.g. a persistent,
+// RocksDB-backed set) can plug in their own Filter implementation in place
+// of BloomFilter or ExactFilter.
+type Filter interface {
+	Seen(word string) bool
+}
+
+// BloomFilter is a probabilistic Filter: it never forgets a word it has
+// seen, but may occasionally report a false positive for a word it has not,
+// at a rate bounded by the false-positive rate it was sized with.
+type BloomFilter struct {
+	mu     sync.Mutex
+	filter *bloom.BloomFilter
+}
+
+// NewBloomFilter sizes a Bloom filter for roughly capacity candidates at the
+// given false-positive rate.
+func NewBloomFilter(capacity uint, falsePositiveRate float64) *BloomFilter {
+	return &BloomFilter{filter: bloom.NewWithEstimates(capacity, falsePositiveRate)}
+}
+
+// Seen hashes word with xxhash and checks it against the Bloom filter,
+// inserting it on a miss.
+func (f *BloomFilter) Seen(word string) bool {
+	key := make([]byte, 8)
+	binary.LittleEndian.PutUint64(key, xxhash.Sum64String(word))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.filter.Test(key) {
+		return true
+	}
+	f.filter.Add(key)
+	return false
+}
+
+// ExactFilter is a correctness-over-memory Filter backed by a set of maps
+// sharded by hash across N mutexes, so concurrent workers don't serialize on
+// a single lock. Unlike BloomFilter it never reports a false positive.
+type ExactFilter struct {
+	shards []exactShard
+	mask   uint64
+}
+
+type exactShard struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewExactFilter creates an ExactFilter with shardCount shards, rounded up
+// to the next power of two so the shard can be picked with a bitmask.
+func NewExactFilter(shardCount int) *ExactFilter {
+	n := 1
+	for n < shardCount {
+		n <<= 1
+	}
+
+	f := &ExactFilter{shards: make([]exactShard, n), mask: uint64(n - 1)}
+	for i := range f.shards {
+		f.shards[i].seen = make(map[string]struct{})
+	}
+	return f
+}
+
+// Seen hashes word to pick a shard, then checks and inserts the word itself
+// (not the hash) in that shard's map, so the result is exact regardless of
+// hash collisions.
+func (f *ExactFilter) Seen(word string) bool {
+	shard := &f.shards[xxhash.Sum64String(word)&f.mask]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, ok := shard.seen[word]; ok {
+		return true
+	}
+	shard.seen[word] = struct{}{}
+	return false
+}
@@ -0,0 +1,107 @@
+package dedup
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestExactFilterSeen(t *testing.T) {
+	f := NewExactFilter(4)
+
+	if f.Seen("password") {
+		t.Fatal("first Seen(\"password\") = true, want false")
+	}
+	if !f.Seen("password") {
+		t.Fatal("second Seen(\"password\") = false, want true")
+	}
+	if f.Seen("p@ssword") {
+		t.Fatal("Seen(\"p@ssword\") = true, want false for a distinct word")
+	}
+}
+
+func TestExactFilterConcurrentSeenHasExactlyOneFirstSighting(t *testing.T) {
+	f := NewExactFilter(8)
+	const workers = 50
+
+	var firstSightings int32
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			if !f.Seen("same-word") {
+				mu.Lock()
+				firstSightings++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstSightings != 1 {
+		t.Fatalf("got %d first sightings of the same word across %d concurrent goroutines, want exactly 1", firstSightings, workers)
+	}
+}
+
+func TestExactFilterConcurrentSeenDistinctWords(t *testing.T) {
+	f := NewExactFilter(8)
+	const n = 500
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			word := fmt.Sprintf("word-%d", i)
+			if f.Seen(word) {
+				t.Errorf("Seen(%q) = true on first sighting", word)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		word := fmt.Sprintf("word-%d", i)
+		if !f.Seen(word) {
+			t.Errorf("Seen(%q) = false on second sighting, want true", word)
+		}
+	}
+}
+
+func TestBloomFilterSeen(t *testing.T) {
+	f := NewBloomFilter(1000, 0.01)
+
+	if f.Seen("password") {
+		t.Fatal("first Seen(\"password\") = true, want false")
+	}
+	if !f.Seen("password") {
+		t.Fatal("second Seen(\"password\") = false, want true")
+	}
+}
+
+func TestBloomFilterConcurrentSeenHasExactlyOneFirstSighting(t *testing.T) {
+	f := NewBloomFilter(1000, 0.01)
+	const workers = 50
+
+	var firstSightings int32
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			if !f.Seen("same-word") {
+				mu.Lock()
+				firstSightings++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstSightings != 1 {
+		t.Fatalf("got %d first sightings of the same word across %d concurrent goroutines, want exactly 1", firstSightings, workers)
+	}
+}
@@ -12,17 +12,28 @@ import (
 	"sync"
 
 	"github.com/alecthomas/kong"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/A113L/hashcat_a5_table_generator/internal/dedup"
 )
 
 // CLI defines the command-line interface structure
 type CLI struct {
-	DictFile      string   `arg:"" help:"Path to dictionary file"`
-	TableFiles    []string `help:"Path to substitution table (multiple possible, sequential)" required:"" short:"t"`
-	TableMin      int      `help:"Minimum substitutions" default:"0" short:"m"`
-	TableMax      int      `help:"Maximum substitutions" default:"15" short:"x"`
-	Threads       int      `help:"Number of threads" default:"-1"`
-	SubstituteAll bool     `help:"Substitution Cipher, see Transliteration Attack" short:"s"`
-	ReverseSub    bool     `help:"Reverse substitution direction" short:"r"`
+	DictFile           string   `arg:"" help:"Path to dictionary file"`
+	TableFiles         []string `help:"Path to substitution table (multiple possible, sequential)" required:"" short:"t"`
+	TableMin           int      `help:"Minimum substitutions" default:"0" short:"m"`
+	TableMax           int      `help:"Maximum substitutions" default:"15" short:"x"`
+	Threads            int      `help:"Number of threads" default:"-1"`
+	SubstituteAll      bool     `help:"Substitution Cipher, see Transliteration Attack" short:"s"`
+	ReverseSub         bool     `help:"Reverse substitution direction" short:"r"`
+	Dedup              bool     `help:"Deduplicate candidates before they reach the writer"`
+	DedupExact         bool     `help:"Use an exact map-based filter instead of a Bloom filter, at higher memory cost"`
+	DedupCapacity      uint     `help:"Estimated number of unique candidates, used to size the Bloom filter" default:"10000000"`
+	DedupFalsePositive float64  `help:"Bloom filter false-positive rate" default:"0.000001"`
+	EmitRules          bool     `help:"Emit a hashcat/JtR rule file describing the substitutions instead of expanding every word"`
+	RuleDialect        string   `help:"Rule dialect to use with --emit-rules" enum:"hashcat,john" default:"hashcat"`
+	Unicode            bool     `help:"NFC-normalize the substitution table and input words before matching. Note: matching itself stays byte-indexed (see matcher); this only makes precomposed vs combining-mark spellings of the same character compare equal"`
+	Confusables        bool     `help:"Seed the substitution map with a curated subset of the Unicode confusables table (UTS #39)"`
 }
 
 func main() {
@@ -39,7 +50,7 @@ func main() {
 
 	substitutionMap := make(map[string][]string)
 	for _, tableFile := range cli.TableFiles {
-		tableMap, err := readSubstitutionTable(tableFile)
+		tableMap, err := readSubstitutionTable(tableFile, cli.Unicode)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -49,29 +60,70 @@ func main() {
 		}
 	}
 
+	if cli.Confusables {
+		for key, values := range confusablesMap() {
+			for _, value := range values {
+				if !contains(substitutionMap[key], value) {
+					substitutionMap[key] = append(substitutionMap[key], value)
+				}
+			}
+		}
+	}
+
+	matcher := newMatcher(substitutionMap)
+
+	var dedupFilter dedup.Filter
+	if cli.Dedup {
+		if cli.DedupExact {
+			dedupFilter = dedup.NewExactFilter(cli.Threads * 4)
+		} else {
+			dedupFilter = dedup.NewBloomFilter(cli.DedupCapacity, cli.DedupFalsePositive)
+		}
+	}
+
 	wordlistFile, err := os.Open(cli.DictFile)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer wordlistFile.Close()
 
-	outputChan := make(chan string, 1000)
+	outputChan := make(chan candidate, 1000)
+
+	var unsupported uint64
 	var writerWg sync.WaitGroup
 	writerWg.Add(1)
 	go func() {
 		defer writerWg.Done()
 		writer := bufio.NewWriter(os.Stdout)
 		defer writer.Flush()
-		for s := range outputChan {
-			writer.WriteString(s + "\n")
+		if cli.EmitRules {
+			ruleWriter(outputChan, writer, ruleDialect(cli.RuleDialect), &unsupported)
+			return
+		}
+		for c := range outputChan {
+			writer.WriteString(c.word + "\n")
 		}
 	}()
 
+	// Each worker below calls dedupFilter.Seen directly from its own
+	// goroutine before forwarding to outputChan, so dedup checks run with
+	// the same concurrency as the workers themselves instead of being
+	// serialized through a single stage.
+	sink := func(c candidate) {
+		if dedupFilter != nil && dedupFilter.Seen(c.word) {
+			return
+		}
+		outputChan <- c
+	}
+
 	sem := make(chan struct{}, cli.Threads)
 	var wg sync.WaitGroup
 	scanner := bufio.NewScanner(wordlistFile)
 	for scanner.Scan() {
 		word := scanner.Text()
+		if cli.Unicode {
+			word = norm.NFC.String(word)
+		}
 		sem <- struct{}{}
 		wg.Add(1)
 		go func(password string) {
@@ -79,15 +131,15 @@ func main() {
 			defer func() { <-sem }()
 			if cli.SubstituteAll {
 				if cli.ReverseSub {
-					processWordSubstituteAllReverse(password, substitutionMap, cli.TableMin, cli.TableMax, outputChan)
+					processWordSubstituteAllReverse(password, matcher, cli.TableMin, cli.TableMax, sink)
 				} else {
-					processWordSubstituteAll(password, substitutionMap, cli.TableMin, cli.TableMax, outputChan)
+					processWordSubstituteAll(password, matcher, cli.TableMin, cli.TableMax, sink)
 				}
 			} else {
 				if cli.ReverseSub {
-					processWordReverse(password, substitutionMap, cli.TableMin, cli.TableMax, outputChan)
+					processWordReverse(password, matcher, cli.TableMin, cli.TableMax, sink)
 				} else {
-					processWord(password, substitutionMap, cli.TableMin, cli.TableMax, outputChan)
+					processWord(password, matcher, cli.TableMin, cli.TableMax, sink)
 				}
 			}
 		}(word)
@@ -96,6 +148,11 @@ func main() {
 	wg.Wait()
 	close(outputChan)
 	writerWg.Wait()
+
+	if cli.EmitRules && unsupported > 0 {
+		log.Printf("%d candidate(s) could not be expressed as a rule and were emitted as expanded words", unsupported)
+	}
+
 	ctx.Exit(0)
 }
 
@@ -105,7 +162,9 @@ func main() {
 // is added to a map where the key is a rune and the value is a slice of runes representing
 // possible substitutions. The function returns this map along with any error encountered
 // during the reading process. This support $HEX[] notation on both sides and is also required to substitute =
-func readSubstitutionTable(path string) (map[string][]string, error) {
+// When normalize is true, both sides are NFC-normalized after hex decoding, so keys and values
+// written with precomposed or combining-mark Unicode sequences match the same way.
+func readSubstitutionTable(path string, normalize bool) (map[string][]string, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -138,11 +197,28 @@ func readSubstitutionTable(path string) (map[string][]string, error) {
 			continue
 		}
 
+		if normalize {
+			decodedKey = norm.NFC.String(decodedKey)
+			decodedValue = norm.NFC.String(decodedValue)
+		}
+
 		substitutions[decodedKey] = append(substitutions[decodedKey], decodedValue)
 	}
 	return substitutions, scanner.Err()
 }
 
+// contains reports whether value is already present in values, so merging
+// the confusables table into a user-supplied substitution table doesn't
+// produce duplicate substitution options for the same key.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // decodeHexNotation decodes the hashcat HEX notation to their respective bytes
 func decodeHexNotation(value string) (string, error) {
 	// Check if value starts with $HEX[ and ends with ]
@@ -161,67 +237,209 @@ func decodeHexNotation(value string) (string, error) {
 	return string(decodedBytes), nil
 }
 
+// acNode is a single state of the Aho–Corasick automaton built over the
+// substitution table keys. children is the goto function for this state,
+// keyed by byte. key is the substitution key that terminates at this node,
+// or "" if no key ends here. output holds every key that matches when the
+// automaton is in this state: the node's own key (if any) followed by the
+// output of the node reached by its failure link, i.e. every proper suffix
+// of the current path that is itself a key.
+type acNode struct {
+	children map[byte]int
+	fail     int
+	key      string
+	output   []string
+}
+
+// acMatch describes one substitution site found while scanning a word:
+// subMap[key] can be applied at [start, start+length).
+type acMatch struct {
+	start, length int
+	key           string
+}
+
+// matcher is an Aho–Corasick automaton built from the keys of a substitution
+// table. It replaces probing every key length at every position (or looping
+// over every pattern) with a single left-to-right scan that reports every
+// match in O(len(word) + matches).
+//
+// Indexing stays byte-based, not rune- or grapheme-cluster-based: every
+// match start/length findAll reports corresponds exactly to a full key from
+// the substitution table, since the automaton only ever transitions on
+// literal key bytes, so a match can never begin or end mid-rune. --unicode
+// (see readSubstitutionTable) only NFC-normalizes table entries and input
+// words before they reach the matcher; it does not re-derive indices in
+// rune or grapheme-cluster units.
+type matcher struct {
+	subMap map[string][]string
+	nodes  []acNode
+}
+
+// newMatcher builds the trie and failure links for subMap's keys once, so it
+// can be reused to scan every word in the dictionary.
+func newMatcher(subMap map[string][]string) *matcher {
+	m := &matcher{
+		subMap: subMap,
+		nodes:  []acNode{{children: make(map[byte]int)}}, // root
+	}
+
+	for key := range subMap {
+		m.insert(key)
+	}
+	m.buildFailureLinks()
+	return m
+}
+
+// insert adds key to the trie, creating nodes for any byte not already on
+// the path from the root.
+func (m *matcher) insert(key string) {
+	state := 0
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		next, ok := m.nodes[state].children[b]
+		if !ok {
+			m.nodes = append(m.nodes, acNode{children: make(map[byte]int)})
+			next = len(m.nodes) - 1
+			m.nodes[state].children[b] = next
+		}
+		state = next
+	}
+	m.nodes[state].key = key
+}
+
+// buildFailureLinks computes the failure link of every node with a BFS from
+// the root, then derives each node's output list (its own key, if any,
+// followed by the output already computed for the node its failure link
+// points to).
+func (m *matcher) buildFailureLinks() {
+	var queue []int
+	for _, child := range m.nodes[0].children {
+		m.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		if m.nodes[state].key != "" {
+			m.nodes[state].output = append(m.nodes[state].output, m.nodes[state].key)
+		}
+		m.nodes[state].output = append(m.nodes[state].output, m.nodes[m.nodes[state].fail].output...)
+
+		for b, child := range m.nodes[state].children {
+			m.nodes[child].fail = m.goTo(m.nodes[state].fail, b)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// goTo follows the failure links from state until it finds a transition on
+// b, falling back to the root if none exists.
+func (m *matcher) goTo(state int, b byte) int {
+	for {
+		if next, ok := m.nodes[state].children[b]; ok {
+			return next
+		}
+		if state == 0 {
+			return 0
+		}
+		state = m.nodes[state].fail
+	}
+}
+
+// findAll scans word once and returns every substitution site in it, in the
+// order the automaton reports them (increasing end position, longest match
+// first at a given end position).
+func (m *matcher) findAll(word string) []acMatch {
+	var matches []acMatch
+	state := 0
+	for i := 0; i < len(word); i++ {
+		state = m.goTo(state, word[i])
+		for _, key := range m.nodes[state].output {
+			start := i - len(key) + 1
+			matches = append(matches, acMatch{start: start, length: len(key), key: key})
+		}
+	}
+	return matches
+}
+
+// matchesByStart groups findAll's matches by start position, with each
+// group sorted longest match first, mirroring the longest-first key length
+// probing processWord used to do by hand.
+func (m *matcher) matchesByStart(word string) map[int][]acMatch {
+	grouped := make(map[int][]acMatch)
+	for _, match := range m.findAll(word) {
+		grouped[match.start] = append(grouped[match.start], match)
+	}
+	for _, group := range grouped {
+		sort.Slice(group, func(a, b int) bool { return group[a].length > group[b].length })
+	}
+	return grouped
+}
+
 // processWord generates all possible variations of the given word by substituting
 // runes according to the given substitution map. The generated words are sent on
 // the given channel. The generation process is limited to a minimum and maximum
-// number of substitutions.
-func processWord(word string, subMap map[string][]string, minSubstitute, maxSubstitute int, out chan<- string) {
+// number of substitutions. Substitution sites at each recursion depth are located
+// with a single Aho–Corasick scan instead of probing every key length at every
+// position.
+func processWord(word string, m *matcher, minSubstitute, maxSubstitute int, out candidateSink) {
 	if minSubstitute == 0 {
 		minSubstitute += 1
 	}
 
-	var generate func(currentWord string, currentSubCount, start int)
-	generate = func(currentWord string, currentSubCount, start int) {
-		for i := start; i < len(currentWord); i++ {
-			// Try all possible key lengths from longest to shortest
-			for keyLength := len(currentWord) - i; keyLength >= 1; keyLength-- {
-				if i+keyLength > len(currentWord) {
-					continue
-				}
-
-				key := currentWord[i : i+keyLength]
-				if subs, ok := subMap[key]; ok {
-					for _, sub := range subs {
-						newWord := currentWord[:i] + sub + currentWord[i+keyLength:]
-						newSubCount := currentSubCount + 1
+	var generate func(currentWord string, currentSubCount, start int, subsSoFar []substitution)
+	generate = func(currentWord string, currentSubCount, start int, subsSoFar []substitution) {
+		matchesByStart := m.matchesByStart(currentWord)
 
-						if newSubCount > maxSubstitute {
-							continue
-						}
+		for i := start; i < len(currentWord); i++ {
+			// Matches at this position are already ordered longest-first.
+			for _, match := range matchesByStart[i] {
+				for _, sub := range m.subMap[match.key] {
+					newWord := currentWord[:i] + sub + currentWord[i+match.length:]
+					newSubCount := currentSubCount + 1
+
+					if newSubCount > maxSubstitute {
+						continue
+					}
 
-						if newSubCount >= minSubstitute {
-							out <- newWord
-						}
+					newSubs := append(append([]substitution{}, subsSoFar...), substitution{pos: i, key: match.key, value: sub})
 
-						// Skip past the substituted part to avoid overlapping substitutions
-						generate(newWord, newSubCount, i+len(sub))
+					if newSubCount >= minSubstitute {
+						out(candidate{word: newWord, subs: newSubs})
 					}
+
+					// Skip past the substituted part to avoid overlapping substitutions
+					generate(newWord, newSubCount, i+len(sub), newSubs)
 				}
 			}
 		}
 	}
 
-	generate(word, 0, 0)
+	generate(word, 0, 0, nil)
 }
 
 // processWordReverse generates variations starting from maximum substitutions down to minimum
-func processWordReverse(word string, subMap map[string][]string, minSubstitute, maxSubstitute int, out chan<- string) {
-	// Find all possible substitution positions
-	var positions []struct {
+func processWordReverse(word string, m *matcher, minSubstitute, maxSubstitute int, out candidateSink) {
+	// Find all possible substitution positions in a single scan.
+	matches := m.findAll(word)
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].start != matches[b].start {
+			return matches[a].start < matches[b].start
+		}
+		return matches[a].length < matches[b].length
+	})
+
+	positions := make([]struct {
 		start, keyLength int
 		subs             []string
-	}
-
-	for i := 0; i < len(word); i++ {
-		for keyLength := 1; keyLength <= len(word)-i; keyLength++ {
-			key := word[i : i+keyLength]
-			if subs, ok := subMap[key]; ok {
-				positions = append(positions, struct {
-					start, keyLength int
-					subs             []string
-				}{i, keyLength, subs})
-			}
-		}
+	}, len(matches))
+	for i, match := range matches {
+		positions[i] = struct {
+			start, keyLength int
+			subs             []string
+		}{match.start, match.length, m.subMap[match.key]}
 	}
 
 	totalPossible := len(positions)
@@ -245,17 +463,27 @@ func processWordReverse(word string, subMap map[string][]string, minSubstitute,
 				continue
 			}
 
-			// Apply first substitution option for each position
+			// Apply first substitution option for each position, left to
+			// right, so the cumulative offset lines up with each edit's
+			// actual position. generateCombinations emits indices in
+			// descending order, but positions (and therefore combo once
+			// sorted) are ordered by ascending start.
+			orderedCombo := append([]int{}, combo...)
+			sort.Ints(orderedCombo)
+
 			result := word
 			offset := 0
-			for _, idx := range combo {
+			var subs []substitution
+			for _, idx := range orderedCombo {
 				pos := positions[idx]
 				sub := pos.subs[0]
 				actualStart := pos.start + offset
+				key := result[actualStart : actualStart+pos.keyLength]
 				result = result[:actualStart] + sub + result[actualStart+pos.keyLength:]
+				subs = append(subs, substitution{pos: actualStart, key: key, value: sub})
 				offset += len(sub) - pos.keyLength
 			}
-			out <- result
+			out(candidate{word: result, subs: subs})
 		}
 	}
 }
@@ -305,17 +533,11 @@ func validSubstitutionPositions(combo []int, positions []struct {
 }
 
 // processWordSubstituteAll generates variations by replacing all occurrences of each substituted character.
-func processWordSubstituteAll(word string, subMap map[string][]string, minSubstitute, maxSubstitute int, out chan<- string) {
-	// Find all unique substitutable patterns in the word
+func processWordSubstituteAll(word string, m *matcher, minSubstitute, maxSubstitute int, out candidateSink) {
+	// Find all unique substitutable patterns in the word with a single scan.
 	uniquePatterns := make(map[string]bool)
-
-	// Scan through the word to find all possible substitution patterns
-	for i := 0; i < len(word); i++ {
-		for pattern := range subMap {
-			if i+len(pattern) <= len(word) && word[i:i+len(pattern)] == pattern {
-				uniquePatterns[pattern] = true
-			}
-		}
+	for _, match := range m.findAll(word) {
+		uniquePatterns[match.key] = true
 	}
 
 	// Convert to slice for deterministic processing
@@ -336,17 +558,19 @@ func processWordSubstituteAll(word string, subMap map[string][]string, minSubsti
 
 			// Apply substitutions to all occurrences
 			result := word
+			subs := make([]substitution, 0, len(currentSubs))
 			for pattern, replacement := range currentSubs {
 				result = strings.ReplaceAll(result, pattern, replacement)
+				subs = append(subs, substitution{key: pattern, value: replacement, global: true})
 			}
-			out <- result
+			out(candidate{word: result, subs: subs})
 			return
 		}
 
 		currentPattern := patterns[pos]
 
 		// For each possible substitution of this pattern
-		for _, sub := range subMap[currentPattern] {
+		for _, sub := range m.subMap[currentPattern] {
 			// Create a new substitution map for this branch
 			newSubs := make(map[string]string, len(currentSubs)+1)
 			for k, v := range currentSubs {
@@ -366,16 +590,11 @@ func processWordSubstituteAll(word string, subMap map[string][]string, minSubsti
 
 // processWordSubstituteAllReverse generates variations by starting with all substitutions
 // and progressively removing them down to the minimum count
-func processWordSubstituteAllReverse(word string, subMap map[string][]string, minSubstitute, maxSubstitute int, out chan<- string) {
-	// Find all unique substitutable patterns in the word
+func processWordSubstituteAllReverse(word string, m *matcher, minSubstitute, maxSubstitute int, out candidateSink) {
+	// Find all unique substitutable patterns in the word with a single scan.
 	uniquePatterns := make(map[string]bool)
-
-	for i := 0; i < len(word); i++ {
-		for pattern := range subMap {
-			if i+len(pattern) <= len(word) && word[i:i+len(pattern)] == pattern {
-				uniquePatterns[pattern] = true
-			}
-		}
+	for _, match := range m.findAll(word) {
+		uniquePatterns[match.key] = true
 	}
 
 	patterns := make([]string, 0, len(uniquePatterns))
@@ -392,7 +611,7 @@ func processWordSubstituteAllReverse(word string, subMap map[string][]string, mi
 	// Start with all possible substitutions (using first substitution option for each)
 	allSubs := make(map[string]string)
 	for _, pattern := range patterns {
-		if subs, ok := subMap[pattern]; ok && len(subs) > 0 {
+		if subs, ok := m.subMap[pattern]; ok && len(subs) > 0 {
 			allSubs[pattern] = subs[0] // Use first substitution option
 		}
 	}
@@ -408,10 +627,12 @@ func processWordSubstituteAllReverse(word string, subMap map[string][]string, mi
 		if currentCount <= maxSubstitute {
 			// Apply substitutions to all occurrences
 			result := word
+			subs := make([]substitution, 0, len(currentSubs))
 			for pattern, replacement := range currentSubs {
 				result = strings.ReplaceAll(result, pattern, replacement)
+				subs = append(subs, substitution{key: pattern, value: replacement, global: true})
 			}
-			out <- string(result)
+			out(candidate{word: result, subs: subs})
 		}
 
 		// Skip if we've reached the minimum
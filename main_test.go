@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMatcherFindAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		table map[string][]string
+		word  string
+		want  []acMatch
+	}{
+		{
+			name:  "overlapping keys",
+			table: map[string][]string{"ab": {"X"}, "b": {"Y"}},
+			word:  "cab",
+			want: []acMatch{
+				{start: 1, length: 2, key: "ab"},
+				{start: 2, length: 1, key: "b"},
+			},
+		},
+		{
+			name:  "shared prefix and suffix",
+			table: map[string][]string{"he": {"X"}, "hello": {"Y"}, "llo": {"Z"}},
+			word:  "hello",
+			want: []acMatch{
+				{start: 0, length: 2, key: "he"},
+				{start: 0, length: 5, key: "hello"},
+				{start: 2, length: 3, key: "llo"},
+			},
+		},
+		{
+			name:  "multi-byte key",
+			table: map[string][]string{"а": {"a"}}, // Cyrillic а, U+0430, 2 bytes in UTF-8
+			word:  "пароль",
+			want:  []acMatch{{start: 2, length: 2, key: "а"}},
+		},
+		{
+			name:  "no matches",
+			table: map[string][]string{"xyz": {"X"}},
+			word:  "hello",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMatcher(tt.table)
+			got := m.findAll(tt.word)
+			sortMatches(got)
+			want := tt.want
+			sortMatches(want)
+
+			if len(got) == 0 && len(want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("findAll(%q) = %+v, want %+v", tt.word, got, want)
+			}
+		})
+	}
+}
+
+func sortMatches(matches []acMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].length < matches[j].length
+	})
+}
+
+// TestProcessWordReverseVariableLengthSubstitutions is a regression test for
+// a bug where processWordReverse applied a combination's edits in
+// generateCombinations' (descending) index order while accumulating a
+// forward offset, corrupting later byte ranges whenever substitutions
+// changed the word's length. It compares processWordReverse's output
+// against processWord's, which should produce the identical set of
+// candidates for this single-option, non-overlapping table.
+func TestProcessWordReverseVariableLengthSubstitutions(t *testing.T) {
+	table := map[string][]string{
+		"a": {"@@"},
+		"o": {"0"},
+		"s": {"$$$"},
+	}
+	m := newMatcher(table)
+	word := "passwords"
+
+	forward := collectCandidateWords(t, func(out candidateSink) { processWord(word, m, 1, 4, out) })
+	reverse := collectCandidateWords(t, func(out candidateSink) { processWordReverse(word, m, 1, 4, out) })
+
+	if len(reverse) == 0 {
+		t.Fatal("processWordReverse produced no candidates")
+	}
+
+	for w := range reverse {
+		if !utf8.ValidString(w) {
+			t.Errorf("processWordReverse produced invalid UTF-8: %q", w)
+		}
+	}
+
+	if !reflect.DeepEqual(forward, reverse) {
+		t.Fatalf("processWordReverse diverged from processWord\nforward-only: %v\nreverse-only: %v",
+			missingFrom(forward, reverse), missingFrom(reverse, forward))
+	}
+}
+
+func collectCandidateWords(t *testing.T, run func(candidateSink)) map[string]bool {
+	t.Helper()
+	words := make(map[string]bool)
+	run(func(c candidate) { words[c.word] = true })
+	return words
+}
+
+func missingFrom(a, b map[string]bool) []string {
+	var out []string
+	for k := range a {
+		if !b[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// substitution records one key->value substitution applied while generating
+// a candidate, in the order it was applied. pos is the position in the
+// candidate's working buffer at the time of the edit, and is meaningless
+// when global is true: those substitutions replace every occurrence of key,
+// the same way hashcat's sXY rule function does.
+type substitution struct {
+	pos        int
+	key, value string
+	global     bool
+}
+
+// candidate pairs a generated word with the ordered substitutions that
+// produced it, so an alternative sink (e.g. ruleWriter) can describe the
+// same transformation as a rule instead of expanding the word itself.
+type candidate struct {
+	word string
+	subs []substitution
+}
+
+// candidateSink receives the candidates a processor generates. main builds
+// one per worker goroutine so dedup filtering happens on the goroutine that
+// found the candidate, instead of funneling every worker through a single
+// serialized stage.
+type candidateSink func(candidate)
+
+// ruleDialect selects the rule syntax toRule emits.
+type ruleDialect string
+
+const (
+	dialectHashcat ruleDialect = "hashcat"
+	dialectJohn    ruleDialect = "john"
+)
+
+// ruleWriter consumes candidates and, for each one, writes a rule line
+// describing the substitutions that produced it instead of the expanded
+// word. A candidate whose substitutions can't be expressed as a rule (a
+// multi-byte key, or a multi-byte global replacement) falls back to writing
+// the expanded word instead, and is counted in *unsupported.
+func ruleWriter(candidates <-chan candidate, writer *bufio.Writer, dialect ruleDialect, unsupported *uint64) {
+	for c := range candidates {
+		rule, ok := toRule(c.subs, dialect)
+		if !ok {
+			atomic.AddUint64(unsupported, 1)
+			writer.WriteString(c.word + "\n")
+			continue
+		}
+		writer.WriteString(rule + "\n")
+	}
+}
+
+// toRule renders subs as a single rule line in the given dialect. It
+// reports false if any substitution can't be expressed with the rule
+// primitives below, in which case the caller should fall back to the
+// expanded word.
+func toRule(subs []substitution, dialect ruleDialect) (string, bool) {
+	if len(subs) == 0 {
+		return ":", true // hashcat/JtR no-op rule: pass the word through unchanged
+	}
+
+	var tokens []string
+	for _, sub := range subs {
+		subTokens, ok := toRuleTokens(sub, dialect)
+		if !ok {
+			return "", false
+		}
+		tokens = append(tokens, subTokens...)
+	}
+	return strings.Join(tokens, " "), true
+}
+
+// toRuleTokens renders a single substitution as one or more rule tokens.
+// A global substitution becomes sXY ("replace every X with Y"), which only
+// exists for a single-character X and Y. A position-specific substitution
+// of a single source character becomes an overwrite (oNX), or an overwrite
+// followed by inserts (iNX) when the replacement is more than one
+// character. Multi-byte keys, and positions past what the dialect's
+// position notation (see ruleIndex) can encode, have no rule equivalent and
+// are reported as unsupported.
+func toRuleTokens(sub substitution, dialect ruleDialect) ([]string, bool) {
+	if sub.global {
+		if len(sub.key) != 1 || len(sub.value) != 1 {
+			return nil, false
+		}
+		return []string{sTok(sub.key[0], sub.value[0])}, true
+	}
+
+	if len(sub.key) != 1 {
+		return nil, false
+	}
+
+	if sub.value == "" {
+		return dTok(sub.pos, dialect)
+	}
+
+	oToken, ok := oTok(sub.pos, sub.value[0], dialect)
+	if !ok {
+		return nil, false
+	}
+	tokens := []string{oToken}
+	for i := 1; i < len(sub.value); i++ {
+		iToken, ok := iTok(sub.pos+i, sub.value[i], dialect)
+		if !ok {
+			return nil, false
+		}
+		tokens = append(tokens, iToken)
+	}
+	return tokens, true
+}
+
+// sTok renders a global replace-every-X-with-Y rule. This is "sXY" in both
+// hashcat and classic John the Ripper; JtR has no "s/X/Y/" sed-like form.
+func sTok(x, y byte) string {
+	return fmt.Sprintf("s%s%s", ruleChar(x), ruleChar(y))
+}
+
+func oTok(pos int, x byte, dialect ruleDialect) (string, bool) {
+	idx, ok := ruleIndex(pos, dialect)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("o%s%s", idx, ruleChar(x)), true
+}
+
+func iTok(pos int, x byte, dialect ruleDialect) (string, bool) {
+	idx, ok := ruleIndex(pos, dialect)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("i%s%s", idx, ruleChar(x)), true
+}
+
+func dTok(pos int, dialect ruleDialect) ([]string, bool) {
+	idx, ok := ruleIndex(pos, dialect)
+	if !ok {
+		return nil, false
+	}
+	return []string{fmt.Sprintf("D%s", idx)}, true
+}
+
+// ruleIndex renders a buffer position in the dialect's position notation,
+// reporting false if pos is out of range rather than clamping to the last
+// valid position (which would silently describe an edit at the wrong
+// offset). Classic John the Ripper only defines 0-9 then A-Z (36
+// positions, per https://www.openwall.com/john/doc/RULES.shtml); hashcat
+// extends that with a-z for 62 positions total.
+func ruleIndex(pos int, dialect ruleDialect) (string, bool) {
+	digits := "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	if dialect != dialectJohn {
+		digits += "abcdefghijklmnopqrstuvwxyz"
+	}
+	if pos < 0 || pos >= len(digits) {
+		return "", false
+	}
+	return string(digits[pos]), true
+}
+
+// ruleChar escapes a byte that isn't a plain printable rule character using
+// hashcat's \xNN extension.
+func ruleChar(b byte) string {
+	if b < 0x21 || b > 0x7e || b == '\\' {
+		return fmt.Sprintf(`\x%02x`, b)
+	}
+	return string(b)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestToRuleTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     substitution
+		dialect ruleDialect
+		want    string
+		wantOk  bool
+	}{
+		{
+			name:    "global single-char substitution",
+			sub:     substitution{key: "a", value: "@", global: true},
+			dialect: dialectHashcat,
+			want:    "sa@",
+			wantOk:  true,
+		},
+		{
+			name:    "global substitution rejects multi-byte key",
+			sub:     substitution{key: "ab", value: "@", global: true},
+			dialect: dialectHashcat,
+			wantOk:  false,
+		},
+		{
+			name:    "positional overwrite",
+			sub:     substitution{pos: 3, key: "a", value: "@"},
+			dialect: dialectHashcat,
+			want:    "o3@",
+			wantOk:  true,
+		},
+		{
+			name:    "positional overwrite plus inserts for a longer value",
+			sub:     substitution{pos: 3, key: "a", value: "abc"},
+			dialect: dialectHashcat,
+			want:    "o3a i4b i5c",
+			wantOk:  true,
+		},
+		{
+			name:    "deletion",
+			sub:     substitution{pos: 2, key: "a", value: ""},
+			dialect: dialectHashcat,
+			want:    "D2",
+			wantOk:  true,
+		},
+		{
+			name:    "positional substitution rejects multi-byte key",
+			sub:     substitution{pos: 0, key: "ab", value: "x"},
+			dialect: dialectHashcat,
+			wantOk:  false,
+		},
+		{
+			name:    "hashcat encodes position 61 as lowercase z",
+			sub:     substitution{pos: 61, key: "a", value: "@"},
+			dialect: dialectHashcat,
+			want:    "oz@",
+			wantOk:  true,
+		},
+		{
+			name:    "hashcat rejects position 62",
+			sub:     substitution{pos: 62, key: "a", value: "@"},
+			dialect: dialectHashcat,
+			wantOk:  false,
+		},
+		{
+			name:    "john rejects lowercase-range position 36 that hashcat would accept",
+			sub:     substitution{pos: 36, key: "a", value: "@"},
+			dialect: dialectJohn,
+			wantOk:  false,
+		},
+		{
+			name:    "john accepts position 35 as Z",
+			sub:     substitution{pos: 35, key: "a", value: "@"},
+			dialect: dialectJohn,
+			want:    "oZ@",
+			wantOk:  true,
+		},
+		{
+			name:    "insert chain past the encodable range is rejected, not clamped",
+			sub:     substitution{pos: 60, key: "a", value: "abc"},
+			dialect: dialectHashcat,
+			wantOk:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := toRuleTokens(tt.sub, tt.dialect)
+			if ok != tt.wantOk {
+				t.Fatalf("toRuleTokens(%+v, %v) ok = %v, want %v (tokens: %v)", tt.sub, tt.dialect, ok, tt.wantOk, got)
+			}
+			if !ok {
+				return
+			}
+			if got := strings.Join(got, " "); got != tt.want {
+				t.Fatalf("toRuleTokens(%+v, %v) = %q, want %q", tt.sub, tt.dialect, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		subs   []substitution
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "no substitutions is the no-op rule",
+			subs:   nil,
+			want:   ":",
+			wantOk: true,
+		},
+		{
+			name: "sequential overwrites chain into one rule line",
+			subs: []substitution{
+				{pos: 0, key: "a", value: "@"},
+				{pos: 3, key: "s", value: "$"},
+			},
+			want:   "o0@ o3$",
+			wantOk: true,
+		},
+		{
+			name: "one unsupported substitution fails the whole candidate",
+			subs: []substitution{
+				{pos: 0, key: "a", value: "@"},
+				{pos: 70, key: "s", value: "$"},
+			},
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := toRule(tt.subs, dialectHashcat)
+			if ok != tt.wantOk {
+				t.Fatalf("toRule(%+v) ok = %v, want %v (rule: %q)", tt.subs, ok, tt.wantOk, got)
+			}
+			if ok && got != tt.want {
+				t.Fatalf("toRule(%+v) = %q, want %q", tt.subs, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestToRuleTokensPosition70Wrong is a regression test for a bug where
+// ruleIndex clamped any out-of-range position to the dialect's last digit
+// instead of reporting failure, so a substitution at e.g. byte offset 70
+// silently produced a rule overwriting the wrong position (the last
+// encodable one) rather than being rejected as unsupported.
+func TestToRuleTokensPosition70Wrong(t *testing.T) {
+	sub := substitution{pos: 70, key: "a", value: "@"}
+	tokens, ok := toRuleTokens(sub, dialectHashcat)
+	if ok {
+		t.Fatalf("toRuleTokens(%+v) = %v, ok=true; want ok=false instead of a clamped, wrong-position rule", sub, tokens)
+	}
+}
+
+func TestRuleWriterFallsBackOnUnsupportedSubstitution(t *testing.T) {
+	candidates := make(chan candidate, 2)
+	candidates <- candidate{word: "p@ssw0rd", subs: []substitution{{pos: 1, key: "a", value: "@"}}}
+	candidates <- candidate{word: "unsupportedword", subs: []substitution{{pos: 70, key: "a", value: "@"}}}
+	close(candidates)
+
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	var unsupported uint64
+	ruleWriter(candidates, writer, dialectHashcat, &unsupported)
+	writer.Flush()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d output lines, want 2: %v", len(lines), lines)
+	}
+	if lines[0] != "o1@" {
+		t.Errorf("first line = %q, want rule %q", lines[0], "o1@")
+	}
+	if lines[1] != "unsupportedword" {
+		t.Errorf("second line = %q, want the expanded fallback word %q", lines[1], "unsupportedword")
+	}
+	if got := atomic.LoadUint64(&unsupported); got != 1 {
+		t.Errorf("unsupported counter = %d, want 1", got)
+	}
+}